@@ -0,0 +1,54 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestAnalyzeFixedCodeReDetectsStillBrokenFix guards against the re-analysis
+// step in fixCodeWithDiagnostics silently reporting every candidate fix as
+// clean: writeTempPackageCopy's scratch directory used to have no module
+// context of its own, so `go vet` failed outright with "outside main
+// module" and analyzeFixedCode swallowed that failure as zero diagnostics,
+// capping the repair loop at a single iteration regardless of
+// opts.MaxIterations.
+func TestAnalyzeFixedCodeReDetectsStillBrokenFix(t *testing.T) {
+	dir := t.TempDir()
+	original := filepath.Join(dir, "sample.go")
+	if err := os.WriteFile(original, []byte("package sample\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	const stillBroken = `package sample
+
+import "fmt"
+
+func Bad() string {
+	return fmt.Sprintf("%d", "not a number")
+}
+`
+	diagnostics, err := analyzeFixedCode(stillBroken, original, []string{"vet"})
+	if err != nil {
+		t.Fatalf("analyzeFixedCode: %v", err)
+	}
+	if len(diagnostics) == 0 {
+		t.Fatal("expected analyzeFixedCode to re-detect the still-broken printf fix, got no diagnostics")
+	}
+
+	const fixed = `package sample
+
+import "fmt"
+
+func Bad() string {
+	return fmt.Sprintf("%d", 42)
+}
+`
+	diagnostics, err = analyzeFixedCode(fixed, original, []string{"vet"})
+	if err != nil {
+		t.Fatalf("analyzeFixedCode: %v", err)
+	}
+	if len(diagnostics) != 0 {
+		t.Fatalf("expected no diagnostics for a real fix, got %+v", diagnostics)
+	}
+}
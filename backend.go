@@ -0,0 +1,263 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Backend sends a CodeFixRequest to a concrete LLM provider and returns the
+// normalized response codefixer already knows how to parse.
+type Backend interface {
+	Name() string
+	// Send performs a chat completion request. When streaming is true and
+	// printLive is true, explanation tokens are printed to stdout as they
+	// arrive; callers that dispatch multiple Send calls concurrently (e.g.
+	// `codefixer repo`'s worker pool) must pass printLive=false to avoid
+	// interleaving output from different requests.
+	Send(request CodeFixRequest, streaming bool, printLive bool) (*CodeFixResponse, error)
+	Ping() bool
+}
+
+// newBackend builds the Backend described by cfg.
+func newBackend(cfg Config) (Backend, error) {
+	switch strings.ToLower(cfg.Backend) {
+	case "lmstudio", "ollama", "llamacpp", "openai", "":
+		return &openAICompatibleBackend{
+			name:    cfg.Backend,
+			baseURL: cfg.BaseURL,
+			apiKey:  cfg.APIKey,
+			timeout: cfg.Timeout,
+		}, nil
+	case "anthropic":
+		return &anthropicBackend{
+			baseURL: cfg.BaseURL,
+			apiKey:  cfg.APIKey,
+			timeout: cfg.Timeout,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown backend %q (want lmstudio, openai, ollama, llamacpp or anthropic)", cfg.Backend)
+	}
+}
+
+const maxRetryAttempts = 5
+
+// doWithRetry runs newReq and sends it, retrying with exponential backoff on
+// connection errors and on 429/5xx responses.
+func doWithRetry(client *http.Client, newReq func() (*http.Request, error)) (*http.Response, error) {
+	backoff := 500 * time.Millisecond
+
+	var lastErr error
+	for attempt := 1; attempt <= maxRetryAttempts; attempt++ {
+		req, err := newReq()
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			lastErr = err
+		} else if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			lastErr = fmt.Errorf("%s: %s", resp.Status, body)
+		} else {
+			return resp, nil
+		}
+
+		if attempt == maxRetryAttempts {
+			break
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+	return nil, fmt.Errorf("request failed after %d attempts: %v", maxRetryAttempts, lastErr)
+}
+
+// openAICompatibleBackend talks to any provider that speaks the OpenAI
+// `/chat/completions` wire format: LM Studio, OpenAI itself, Ollama (via its
+// OpenAI-compatible endpoint) and a local llama.cpp server.
+type openAICompatibleBackend struct {
+	name    string
+	baseURL string
+	apiKey  string
+	timeout time.Duration
+}
+
+func (b *openAICompatibleBackend) Name() string {
+	if b.name == "" {
+		return "lmstudio"
+	}
+	return b.name
+}
+
+func (b *openAICompatibleBackend) authorize(req *http.Request) {
+	if b.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+b.apiKey)
+	}
+}
+
+func (b *openAICompatibleBackend) Send(request CodeFixRequest, streaming bool, printLive bool) (*CodeFixResponse, error) {
+	request.Stream = streaming
+	if streaming {
+		return sendChatRequestStream(b, request, printLive)
+	}
+	return sendChatRequestOnce(b, request)
+}
+
+func (b *openAICompatibleBackend) Ping() bool {
+	client := &http.Client{Timeout: 5 * time.Second}
+	req, err := http.NewRequest("GET", b.baseURL+"/models", nil)
+	if err != nil {
+		return false
+	}
+	b.authorize(req)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return false
+	}
+	defer resp.Body.Close()
+	return resp.StatusCode == http.StatusOK
+}
+
+// sendChatRequestOnce performs a single, non-streaming chat completion
+// request against an OpenAI-compatible backend.
+func sendChatRequestOnce(b *openAICompatibleBackend, request CodeFixRequest) (*CodeFixResponse, error) {
+	client := &http.Client{Timeout: b.timeout}
+
+	requestBody, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := doWithRetry(client, func() (*http.Request, error) {
+		req, err := http.NewRequest("POST", b.baseURL+"/chat/completions", bytes.NewBuffer(requestBody))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		b.authorize(req)
+		return req, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("API request failed: %s\nResponse: %s", resp.Status, body)
+	}
+
+	var response CodeFixResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// anthropicBackend talks to the Anthropic Messages API, which uses a
+// different request/response shape than the OpenAI-style backends.
+type anthropicBackend struct {
+	baseURL string
+	apiKey  string
+	timeout time.Duration
+}
+
+func (b *anthropicBackend) Name() string { return "anthropic" }
+
+// anthropicRequest is the subset of the Messages API request shape
+// codefixer needs: a system prompt plus a list of user/assistant turns.
+type anthropicRequest struct {
+	Model     string             `json:"model"`
+	System    string             `json:"system,omitempty"`
+	Messages  []anthropicMessage `json:"messages"`
+	MaxTokens int                `json:"max_tokens"`
+}
+
+type anthropicMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type anthropicResponse struct {
+	Content []struct {
+		Text string `json:"text"`
+	} `json:"content"`
+	Usage struct {
+		InputTokens  int `json:"input_tokens"`
+		OutputTokens int `json:"output_tokens"`
+	} `json:"usage"`
+}
+
+// Send translates request into the Messages API shape and back. Streaming
+// is not implemented for Anthropic yet; it always performs a blocking call,
+// so printLive has no effect here.
+func (b *anthropicBackend) Send(request CodeFixRequest, streaming bool, printLive bool) (*CodeFixResponse, error) {
+	anthReq := anthropicRequest{
+		Model:     request.Model,
+		MaxTokens: 4096,
+	}
+	for _, m := range request.Messages {
+		if m.Role == "system" {
+			anthReq.System = m.Content
+			continue
+		}
+		anthReq.Messages = append(anthReq.Messages, anthropicMessage{Role: m.Role, Content: m.Content})
+	}
+
+	client := &http.Client{Timeout: b.timeout}
+	body, err := json.Marshal(anthReq)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := doWithRetry(client, func() (*http.Request, error) {
+		req, err := http.NewRequest("POST", b.baseURL+"/v1/messages", bytes.NewBuffer(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("x-api-key", b.apiKey)
+		req.Header.Set("anthropic-version", "2023-06-01")
+		return req, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("API request failed: %s\nResponse: %s", resp.Status, respBody)
+	}
+
+	var anthResp anthropicResponse
+	if err := json.NewDecoder(resp.Body).Decode(&anthResp); err != nil {
+		return nil, err
+	}
+
+	var text string
+	if len(anthResp.Content) > 0 {
+		text = anthResp.Content[0].Text
+	}
+
+	return &CodeFixResponse{
+		Choices: []Choice{{Message: ChoiceMessage{Content: text}}},
+		Usage: &Usage{
+			PromptTokens:     anthResp.Usage.InputTokens,
+			CompletionTokens: anthResp.Usage.OutputTokens,
+			TotalTokens:      anthResp.Usage.InputTokens + anthResp.Usage.OutputTokens,
+		},
+	}, nil
+}
+
+func (b *anthropicBackend) Ping() bool {
+	// Anthropic has no lightweight health endpoint; trust the configured key.
+	return b.apiKey != ""
+}
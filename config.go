@@ -0,0 +1,117 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config holds everything needed to talk to an LLM backend. It is built
+// from built-in defaults, then ~/.codefixer.yaml, then environment
+// variables, each layer overriding the last.
+type Config struct {
+	Backend string
+	BaseURL string
+	Model   string
+	APIKey  string
+	Timeout time.Duration
+}
+
+func defaultConfig() Config {
+	return Config{
+		Backend: "lmstudio",
+		Model:   "llama-3.2-1b-instruct",
+		Timeout: 120 * time.Second,
+	}
+}
+
+// loadConfig resolves the active configuration: defaults, overlaid with
+// ~/.codefixer.yaml if present, overlaid with CODEFIXER_* env vars.
+func loadConfig() Config {
+	cfg := defaultConfig()
+
+	if home, err := os.UserHomeDir(); err == nil {
+		cfg.mergeYAMLFile(filepath.Join(home, ".codefixer.yaml"))
+	}
+	cfg.mergeEnv()
+
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = defaultBaseURLFor(cfg.Backend)
+	}
+	return cfg
+}
+
+// mergeYAMLFile applies simple `key: value` overrides from path. Only the
+// handful of scalar keys codefixer cares about are supported, so a full
+// YAML parser isn't worth pulling in.
+func (c *Config) mergeYAMLFile(path string) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		key = strings.TrimSpace(key)
+		value = strings.Trim(strings.TrimSpace(value), `"'`)
+
+		switch key {
+		case "backend":
+			c.Backend = value
+		case "base_url":
+			c.BaseURL = value
+		case "model":
+			c.Model = value
+		case "api_key":
+			c.APIKey = value
+		case "timeout_seconds":
+			if secs, err := strconv.Atoi(value); err == nil {
+				c.Timeout = time.Duration(secs) * time.Second
+			}
+		}
+	}
+}
+
+// mergeEnv applies CODEFIXER_* environment variable overrides.
+func (c *Config) mergeEnv() {
+	if v := os.Getenv("CODEFIXER_BACKEND"); v != "" {
+		c.Backend = v
+	}
+	if v := os.Getenv("CODEFIXER_BASE_URL"); v != "" {
+		c.BaseURL = v
+	}
+	if v := os.Getenv("CODEFIXER_MODEL"); v != "" {
+		c.Model = v
+	}
+	if v := os.Getenv("CODEFIXER_API_KEY"); v != "" {
+		c.APIKey = v
+	}
+}
+
+// defaultBaseURLFor returns the well-known API root for each supported
+// backend, used when the user picks a backend but doesn't set base_url.
+func defaultBaseURLFor(backend string) string {
+	switch strings.ToLower(backend) {
+	case "openai":
+		return "https://api.openai.com/v1"
+	case "anthropic":
+		return "https://api.anthropic.com"
+	case "ollama":
+		return "http://localhost:11434/v1"
+	case "llamacpp":
+		return "http://localhost:8080/v1"
+	case "lmstudio":
+		fallthrough
+	default:
+		return "http://localhost:1234/v1"
+	}
+}
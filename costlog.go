@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// usageLogMu serializes access to the usage log file. `codefixer repo`
+// dispatches multiple fixes concurrently, and without this the read-modify-
+// write in appendUsageRecord would race across goroutines.
+var usageLogMu sync.Mutex
+
+// UsageRecord is one entry in the on-disk spend log: what backend/model
+// served a request and what it cost.
+type UsageRecord struct {
+	Timestamp        string  `json:"timestamp"`
+	Backend          string  `json:"backend"`
+	Model            string  `json:"model"`
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	TotalTokens      int     `json:"total_tokens"`
+	EstimatedCostUSD float64 `json:"estimated_cost_usd"`
+}
+
+// modelPricing holds USD cost per million tokens for hosted models we know
+// about. Local backends (LM Studio, Ollama, llama.cpp) aren't priced and
+// default to zero.
+var modelPricing = map[string]struct {
+	PromptPerMillion     float64
+	CompletionPerMillion float64
+}{
+	"gpt-4o":                     {2.50, 10.00},
+	"gpt-4o-mini":                {0.15, 0.60},
+	"claude-3-5-sonnet-20241022": {3.00, 15.00},
+	"claude-3-5-haiku-20241022":  {0.80, 4.00},
+}
+
+// estimateCost returns the USD cost of usage for the given model, or 0 if
+// the model isn't in the pricing table.
+func estimateCost(model string, usage Usage) float64 {
+	pricing, ok := modelPricing[model]
+	if !ok {
+		return 0
+	}
+	return float64(usage.PromptTokens)/1_000_000*pricing.PromptPerMillion +
+		float64(usage.CompletionTokens)/1_000_000*pricing.CompletionPerMillion
+}
+
+// recordUsage appends a UsageRecord for this request to ~/.codefixer/usage.json
+// so spend can be audited across runs. A nil usage (backend didn't report
+// any) is a no-op.
+func recordUsage(backend, model string, usage *Usage) {
+	if usage == nil {
+		return
+	}
+
+	record := UsageRecord{
+		Timestamp:        time.Now().Format(time.RFC3339),
+		Backend:          backend,
+		Model:            model,
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+		TotalTokens:      usage.TotalTokens,
+		EstimatedCostUSD: estimateCost(model, *usage),
+	}
+
+	if err := appendUsageRecord(record); err != nil {
+		fmt.Printf("warning: failed to record usage: %v\n", err)
+	}
+}
+
+func usageLogPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(home, ".codefixer")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "usage.json"), nil
+}
+
+func appendUsageRecord(record UsageRecord) error {
+	usageLogMu.Lock()
+	defer usageLogMu.Unlock()
+
+	path, err := usageLogPath()
+	if err != nil {
+		return err
+	}
+
+	var records []UsageRecord
+	if data, err := os.ReadFile(path); err == nil {
+		json.Unmarshal(data, &records)
+	}
+	records = append(records, record)
+
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
@@ -0,0 +1,331 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+const diffContextLines = 3
+
+// diffOp is one line of an aligned original/fixed comparison.
+type diffOp struct {
+	kind byte // ' ' (context), '-' (removed), '+' (added)
+	text string
+}
+
+// Hunk is a contiguous group of changed lines plus surrounding context,
+// addressable and reviewable independently of the rest of the diff. Ops is
+// a slice into the full diff's op list (opsStart:opsEnd).
+type Hunk struct {
+	OriginalStart int
+	OriginalLines int
+	FixedStart    int
+	FixedLines    int
+	Ops           []diffOp
+	opsStart      int
+	opsEnd        int
+}
+
+// computeHunks diffs original against fixed line-by-line (via LCS, an
+// internal stand-in for a full Myers implementation) and groups the result
+// into unified-diff-style hunks with diffContextLines of surrounding
+// context on each side. It returns both the hunks and the full op list,
+// since applyHunks needs the untouched context lines between hunks too.
+func computeHunks(original, fixed string) (ops []diffOp, hunks []Hunk) {
+	ops = alignLines(splitLines(original), splitLines(fixed))
+	return ops, groupHunks(ops)
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	lines := strings.Split(s, "\n")
+	if len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// alignLines produces the full line-by-line diff ops (context/add/remove)
+// between a and b using a classic longest-common-subsequence table.
+func alignLines(a, b []string) []diffOp {
+	n, m := len(a), len(b)
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var ops []diffOp
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, diffOp{' ', a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, diffOp{'-', a[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < n; i++ {
+		ops = append(ops, diffOp{'-', a[i]})
+	}
+	for ; j < m; j++ {
+		ops = append(ops, diffOp{'+', b[j]})
+	}
+	return ops
+}
+
+// groupHunks finds every changed line, expands it by diffContextLines of
+// surrounding context, merges overlapping ranges, and turns each range into
+// a Hunk with accurate original/fixed line numbers.
+func groupHunks(ops []diffOp) []Hunk {
+	n := len(ops)
+
+	// origLine[i]/fixedLine[i]: 1-based line number that ops[i] occupies in
+	// the original/fixed file (only meaningful for kinds that exist there).
+	origLine := make([]int, n)
+	fixedLine := make([]int, n)
+	o, f := 1, 1
+	for i, op := range ops {
+		switch op.kind {
+		case ' ':
+			origLine[i], fixedLine[i] = o, f
+			o++
+			f++
+		case '-':
+			origLine[i] = o
+			o++
+		case '+':
+			fixedLine[i] = f
+			f++
+		}
+	}
+
+	var ranges [][2]int
+	for i, op := range ops {
+		if op.kind == ' ' {
+			continue
+		}
+		start := max(0, i-diffContextLines)
+		end := min(n-1, i+diffContextLines)
+		if len(ranges) > 0 && start <= ranges[len(ranges)-1][1]+1 {
+			if end > ranges[len(ranges)-1][1] {
+				ranges[len(ranges)-1][1] = end
+			}
+		} else {
+			ranges = append(ranges, [2]int{start, end})
+		}
+	}
+
+	hunks := make([]Hunk, 0, len(ranges))
+	for _, r := range ranges {
+		start, end := r[0], r[1]
+		h := Hunk{Ops: ops[start : end+1], opsStart: start, opsEnd: end + 1}
+
+		for i := start; i <= end; i++ {
+			if ops[i].kind != '+' {
+				h.OriginalStart = origLine[i]
+				break
+			}
+		}
+		for i := start; i <= end; i++ {
+			if ops[i].kind != '-' {
+				h.FixedStart = fixedLine[i]
+				break
+			}
+		}
+		for i := start; i <= end; i++ {
+			if ops[i].kind != '+' {
+				h.OriginalLines++
+			}
+			if ops[i].kind != '-' {
+				h.FixedLines++
+			}
+		}
+		hunks = append(hunks, h)
+	}
+	return hunks
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// renderHunkHeader formats the `@@ -a,b +c,d @@` header for a hunk.
+func renderHunkHeader(h Hunk) string {
+	return fmt.Sprintf("@@ -%d,%d +%d,%d @@", h.OriginalStart, h.OriginalLines, h.FixedStart, h.FixedLines)
+}
+
+const (
+	colorReset = "\033[0m"
+	colorRed   = "\033[31m"
+	colorGreen = "\033[32m"
+	colorCyan  = "\033[36m"
+)
+
+// printHunk pretty-prints a hunk with colored, indented +/- lines.
+func printHunk(h Hunk, index, total int) {
+	fmt.Printf("%sHunk %d/%d%s %s\n", colorCyan, index, total, colorReset, renderHunkHeader(h))
+	for _, op := range h.Ops {
+		switch op.kind {
+		case '+':
+			fmt.Printf("  %s+ %s%s\n", colorGreen, op.text, colorReset)
+		case '-':
+			fmt.Printf("  %s- %s%s\n", colorRed, op.text, colorReset)
+		default:
+			fmt.Printf("    %s\n", op.text)
+		}
+	}
+}
+
+// renderHunkPatch renders a hunk as a standalone unified diff fragment,
+// used to write rejected hunks out to a .rej file.
+func renderHunkPatch(h Hunk) string {
+	var b strings.Builder
+	b.WriteString(renderHunkHeader(h))
+	b.WriteString("\n")
+	for _, op := range h.Ops {
+		switch op.kind {
+		case '+':
+			b.WriteString("+" + op.text + "\n")
+		case '-':
+			b.WriteString("-" + op.text + "\n")
+		default:
+			b.WriteString(" " + op.text + "\n")
+		}
+	}
+	return b.String()
+}
+
+// reviewHunksInteractive prompts for each hunk in turn, git-add-p style, and
+// returns the set of accepted hunk indices.
+func reviewHunksInteractive(tty io.Reader, hunks []Hunk) (map[int]bool, error) {
+	accepted := make(map[int]bool)
+	reader := bufio.NewReader(tty)
+	acceptRest := false
+	quit := false
+
+	for i, h := range hunks {
+		if quit {
+			break
+		}
+		printHunk(h, i+1, len(hunks))
+
+		if acceptRest {
+			accepted[i] = true
+			continue
+		}
+
+		for {
+			fmt.Print("Apply this hunk [y,n,a,q,s,?]? ")
+			line, err := reader.ReadString('\n')
+			if err != nil && err != io.EOF {
+				return nil, fmt.Errorf("error reading input: %v", err)
+			}
+			choice := strings.ToLower(strings.TrimSpace(line))
+
+			switch choice {
+			case "y":
+				accepted[i] = true
+			case "n", "s":
+				// reject; "s" is the same as "n" and the hunk is not offered again
+			case "a":
+				accepted[i] = true
+				acceptRest = true
+			case "q":
+				quit = true
+			case "?", "":
+				fmt.Println("y - apply this hunk\nn - do not apply this hunk\na - apply this hunk and all later hunks\nq - quit; do not apply this hunk or any later ones\ns - skip this hunk; same as n, it is not offered again")
+				continue
+			default:
+				fmt.Println("unrecognized choice, see ? for help")
+				continue
+			}
+			break
+		}
+	}
+
+	return accepted, nil
+}
+
+// writeRejectFile writes every rejected hunk as a unified-diff fragment to
+// "<originalFile>.rej", mirroring the reject-file convention of `patch`.
+func writeRejectFile(originalFile string, hunks []Hunk, accepted map[int]bool) (string, error) {
+	var b strings.Builder
+	count := 0
+	for i, h := range hunks {
+		if accepted[i] {
+			continue
+		}
+		b.WriteString(renderHunkPatch(h))
+		count++
+	}
+	if count == 0 {
+		return "", nil
+	}
+
+	rejName := originalFile + ".rej"
+	return rejName, os.WriteFile(rejName, []byte(b.String()), 0644)
+}
+
+// applyHunks walks the full op list and stitches the final file content:
+// context lines are kept as-is, and the +/- lines of each hunk are resolved
+// according to whether that hunk was accepted.
+func applyHunks(ops []diffOp, hunks []Hunk, accepted map[int]bool) string {
+	hunkForOp := make(map[int]int, len(ops))
+	for hi, h := range hunks {
+		for i := h.opsStart; i < h.opsEnd; i++ {
+			hunkForOp[i] = hi
+		}
+	}
+
+	var out strings.Builder
+	for i, op := range ops {
+		switch op.kind {
+		case ' ':
+			out.WriteString(op.text)
+			out.WriteString("\n")
+		case '-':
+			if !accepted[hunkForOp[i]] {
+				out.WriteString(op.text)
+				out.WriteString("\n")
+			}
+		case '+':
+			if accepted[hunkForOp[i]] {
+				out.WriteString(op.text)
+				out.WriteString("\n")
+			}
+		}
+	}
+	return out.String()
+}
@@ -0,0 +1,116 @@
+package main
+
+import "testing"
+
+func opsString(ops []diffOp) string {
+	s := ""
+	for _, op := range ops {
+		s += string(op.kind) + op.text + "|"
+	}
+	return s
+}
+
+func TestAlignLines(t *testing.T) {
+	cases := []struct {
+		name     string
+		a, b     []string
+		expected string
+	}{
+		{
+			name:     "identical",
+			a:        []string{"x", "y"},
+			b:        []string{"x", "y"},
+			expected: " x| y|",
+		},
+		{
+			name:     "single line changed",
+			a:        []string{"one", "two", "three"},
+			b:        []string{"one", "TWO", "three"},
+			expected: " one|-two|+TWO| three|",
+		},
+		{
+			name:     "append only",
+			a:        []string{"one"},
+			b:        []string{"one", "two"},
+			expected: " one|+two|",
+		},
+		{
+			name:     "remove only",
+			a:        []string{"one", "two"},
+			b:        []string{"one"},
+			expected: " one|-two|",
+		},
+		{
+			name:     "empty to nonempty",
+			a:        nil,
+			b:        []string{"one"},
+			expected: "+one|",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := opsString(alignLines(c.a, c.b))
+			if got != c.expected {
+				t.Errorf("alignLines(%v, %v) = %q, want %q", c.a, c.b, got, c.expected)
+			}
+		})
+	}
+}
+
+func TestComputeHunksRoundtrip(t *testing.T) {
+	original := "line1\nline2\nline3\nline4\nline5\n"
+	fixed := "line1\nCHANGED\nline3\nline4\nline5\n"
+
+	ops, hunks := computeHunks(original, fixed)
+	if len(hunks) != 1 {
+		t.Fatalf("expected 1 hunk, got %d: %+v", len(hunks), hunks)
+	}
+
+	accepted := map[int]bool{0: true}
+	if got := applyHunks(ops, hunks, accepted); got != fixed {
+		t.Errorf("accepting the hunk should reproduce fixed, got %q", got)
+	}
+
+	rejected := map[int]bool{}
+	if got := applyHunks(ops, hunks, rejected); got != original {
+		t.Errorf("rejecting the hunk should reproduce original, got %q", got)
+	}
+}
+
+func TestComputeHunksNoChanges(t *testing.T) {
+	same := "a\nb\nc\n"
+	_, hunks := computeHunks(same, same)
+	if len(hunks) != 0 {
+		t.Errorf("expected no hunks for identical input, got %d", len(hunks))
+	}
+}
+
+func TestComputeHunksPreservesUnrelatedContext(t *testing.T) {
+	// Two far-apart single-line changes, separated by enough unchanged
+	// lines that they land in separate hunks; applyHunks must still carry
+	// the untouched lines between them through to the final output.
+	var original, fixed string
+	for i := 0; i < 20; i++ {
+		if i == 0 {
+			original += "first\n"
+			fixed += "FIRST\n"
+		} else if i == 19 {
+			original += "last\n"
+			fixed += "LAST\n"
+		} else {
+			original += "mid\n"
+			fixed += "mid\n"
+		}
+	}
+
+	ops, hunks := computeHunks(original, fixed)
+	if len(hunks) != 2 {
+		t.Fatalf("expected 2 separate hunks, got %d", len(hunks))
+	}
+
+	accepted := map[int]bool{0: true, 1: true}
+	if got := applyHunks(ops, hunks, accepted); got != fixed {
+		t.Errorf("accepting both hunks should reproduce fixed, got %q", got)
+	}
+}
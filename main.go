@@ -1,23 +1,15 @@
 package main
 
 import (
-	"bytes"
 	"encoding/json"
+	"flag"
 	"fmt"
-	"io"
-	"net/http"
 	"os"
-	"os/exec"
+	"path/filepath"
 	"strings"
 	"time"
 )
 
-const (
-	baseURL    = "http://localhost:1234/v1"
-	modelName  = "llama-3.2-1b-instruct"
-	apiTimeout = 120 * time.Second
-)
-
 type CodeFixRequest struct {
 	Model          string    `json:"model"`
 	Messages       []Message `json:"messages"`
@@ -26,8 +18,13 @@ type CodeFixRequest struct {
 		Type       string     `json:"type"`
 		JSONSchema JSONSchema `json:"json_schema,omitempty"`
 	} `json:"response_format,omitempty"`
-	Temperature float32 `json:"temperature"`
-	Stream      bool    `json:"stream"`
+	Temperature   float32        `json:"temperature"`
+	Stream        bool           `json:"stream"`
+	StreamOptions *StreamOptions `json:"stream_options,omitempty"`
+}
+
+type StreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
 }
 
 type JSONSchema struct {
@@ -79,13 +76,26 @@ type Parameters struct {
 }
 
 type CodeFixResponse struct {
-	ID      string `json:"id"`
-	Choices []struct {
-		Message struct {
-			Content   string     `json:"content"`
-			ToolCalls []ToolCall `json:"tool_calls"`
-		} `json:"message"`
-	} `json:"choices"`
+	ID      string   `json:"id"`
+	Choices []Choice `json:"choices"`
+	Usage   *Usage   `json:"usage,omitempty"`
+}
+
+// Usage is the OpenAI-style token accounting block; codefixer records it
+// per request for cost auditing regardless of which backend produced it.
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+type Choice struct {
+	Message ChoiceMessage `json:"message"`
+}
+
+type ChoiceMessage struct {
+	Content   string     `json:"content"`
+	ToolCalls []ToolCall `json:"tool_calls"`
 }
 
 type CodeFix struct {
@@ -97,24 +107,46 @@ type CodeFix struct {
 }
 
 func main() {
-	if !checkServerAvailable() {
-		fmt.Println("LM Studio server not available. Please ensure it's running at", baseURL)
+	if len(os.Args) > 1 && os.Args[1] == "repo" {
+		if err := runRepoCommand(os.Args[2:]); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	insecure := flag.Bool("insecure", false, "run language validators without sandboxing (bubblewrap/nsjail)")
+	stream := flag.Bool("stream", true, "stream the LLM response and print explanation tokens as they arrive")
+	noStream := flag.Bool("no-stream", false, "disable streaming and wait for the full response")
+	flag.Parse()
+	streaming := *stream && !*noStream
+
+	cfg := loadConfig()
+	backend, err := newBackend(cfg)
+	if err != nil {
+		fmt.Printf("Error configuring backend: %v\n", err)
+		os.Exit(1)
+	}
+
+	if !backend.Ping() {
+		fmt.Printf("%s backend not available at %s\n", backend.Name(), cfg.BaseURL)
 		os.Exit(1)
 	}
 
-	if len(os.Args) < 2 {
-		fmt.Println("Usage: codefixer <filename>")
+	if flag.NArg() < 1 {
+		fmt.Println("Usage: codefixer [--insecure] <filename>")
+		fmt.Println("       codefixer repo [--build-cmd \"go build ./...\"] [--jobs N]")
 		os.Exit(1)
 	}
 
-	filename := os.Args[1]
+	filename := flag.Arg(0)
 	content, err := os.ReadFile(filename)
 	if err != nil {
 		fmt.Printf("Error reading file: %v\n", err)
 		os.Exit(1)
 	}
 
-	fix, err := analyzeAndFixCode(string(content))
+	fix, err := analyzeAndFixCode(string(content), filename, DefaultAnalyzeOptions, streaming, streaming, backend, cfg)
 	if err != nil {
 		fmt.Printf("Error fixing code: %v\n", err)
 		os.Exit(1)
@@ -126,17 +158,41 @@ func main() {
 	fmt.Println(fix.OriginalCode)
 	fmt.Println("\nFixed Code:")
 	fmt.Println(fix.FixedCode)
-	fmt.Println("\nExplanation:")
-	fmt.Println(fix.Explanation)
+	_, blockingBackend := backend.(*anthropicBackend)
+	if !streaming || blockingBackend {
+		// When streaming through a backend that streams, the explanation was
+		// already printed live by explanationPrinter; printing it again here
+		// would just duplicate it. anthropicBackend always answers in one
+		// blocking call, so nothing was printed live for it.
+		fmt.Println("\nExplanation:")
+		fmt.Println(fix.Explanation)
+	}
 
-	if err := validateAndSave(filename, fix); err != nil {
+	if err := validateAndSave(filename, fix, *insecure); err != nil {
 		fmt.Printf("\nError: %v\n", err)
 	} else {
 		fmt.Println("\nUpdate successful!")
 	}
 }
 
-func analyzeAndFixCode(code string) (*CodeFix, error) {
+func analyzeAndFixCode(code string, filename string, opts AnalyzeOptions, streaming bool, printLive bool, backend Backend, cfg Config) (*CodeFix, error) {
+	diagnostics, err := runAnalyzers(filename, opts.Analyzers)
+	if err != nil {
+		return nil, fmt.Errorf("error running static analyzers: %v", err)
+	}
+
+	return fixCodeWithDiagnostics(code, filename, diagnostics, opts, streaming, printLive, backend, cfg)
+}
+
+// fixCodeWithDiagnostics runs the LLM repair loop for a single file given an
+// initial set of diagnostics (from local static analyzers, or from a
+// project-wide build command in the `repo` subcommand), re-running the
+// configured analyzers after each candidate fix until they're clean or
+// opts.MaxIterations is reached. printLive controls whether streamed
+// explanation tokens are printed as they arrive; callers fixing several
+// files concurrently must pass false to keep stdout from different files
+// from interleaving.
+func fixCodeWithDiagnostics(code string, filename string, diagnostics []Diagnostic, opts AnalyzeOptions, streaming bool, printLive bool, backend Backend, cfg Config) (*CodeFix, error) {
 	tools := []Tool{
 		{
 			Type: "function",
@@ -181,85 +237,89 @@ func analyzeAndFixCode(code string) (*CodeFix, error) {
 				"Use structured JSON output format.",
 		},
 		{
-			Role:    "user",
-			Content: fmt.Sprintf("Analyze and fix this code:\n```\n%s\n```", code),
-		},
-	}
-
-	request := CodeFixRequest{
-		Model:    modelName,
-		Messages: messages,
-		Tools:    tools,
-		ResponseFormat: struct {
-			Type       string     `json:"type"`
-			JSONSchema JSONSchema `json:"json_schema,omitempty"`
-		}{
-			Type:       "json_schema",
-			JSONSchema: schema,
+			Role: "user",
+			Content: fmt.Sprintf("Analyze and fix this code:\n```\n%s\n```%s",
+				code, formatDiagnosticHints(diagnostics)),
 		},
-		Temperature: 0.3,
-		Stream:      false,
-	}
-
-	response, err := sendChatRequest(request)
-	if err != nil {
-		return nil, err
 	}
 
 	var codeFix CodeFix
-	if err := json.Unmarshal([]byte(response.Choices[0].Message.Content), &codeFix); err != nil {
-		return nil, fmt.Errorf("error parsing JSON response: %v", err)
+	maxIterations := opts.MaxIterations
+	if maxIterations < 1 {
+		maxIterations = 1
 	}
 
-	codeFix.OriginalCode = code
-	return &codeFix, nil
-}
+	for iteration := 0; iteration < maxIterations; iteration++ {
+		request := CodeFixRequest{
+			Model:    cfg.Model,
+			Messages: messages,
+			Tools:    tools,
+			ResponseFormat: struct {
+				Type       string     `json:"type"`
+				JSONSchema JSONSchema `json:"json_schema,omitempty"`
+			}{
+				Type:       "json_schema",
+				JSONSchema: schema,
+			},
+			Temperature: 0.3,
+		}
+		if streaming {
+			request.StreamOptions = &StreamOptions{IncludeUsage: true}
+		}
 
-func sendChatRequest(request CodeFixRequest) (*CodeFixResponse, error) {
-	client := &http.Client{Timeout: apiTimeout}
+		response, err := backend.Send(request, streaming, printLive)
+		if err != nil {
+			return nil, err
+		}
+		recordUsage(backend.Name(), cfg.Model, response.Usage)
 
-	requestBody, err := json.Marshal(request)
-	if err != nil {
-		return nil, err
-	}
+		if err := json.Unmarshal([]byte(response.Choices[0].Message.Content), &codeFix); err != nil {
+			return nil, fmt.Errorf("error parsing JSON response: %v", err)
+		}
+		codeFix.OriginalCode = code
 
-	req, err := http.NewRequest("POST", baseURL+"/chat/completions", bytes.NewBuffer(requestBody))
-	if err != nil {
-		return nil, err
-	}
-	req.Header.Set("Content-Type", "application/json")
+		newDiagnostics, err := analyzeFixedCode(codeFix.FixedCode, filename, opts.Analyzers)
+		if err != nil {
+			return nil, fmt.Errorf("error re-running static analyzers: %v", err)
+		}
+		if len(newDiagnostics) == 0 {
+			break
+		}
 
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
+		messages = append(messages,
+			Message{Role: "assistant", Content: response.Choices[0].Message.Content},
+			Message{Role: "user", Content: "The fix still triggers static analysis findings, please address them:" +
+				formatDiagnosticHints(newDiagnostics)},
+		)
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API request failed: %s\nResponse: %s", resp.Status, body)
+	return &codeFix, nil
+}
+
+// analyzeFixedCode writes the LLM's candidate fix into a scratch copy of its
+// package (alongside copies of its sibling files) and re-runs the
+// configured analyzers against it, so the repair loop can judge whether the
+// fix actually resolved the original findings without sibling-file
+// `undefined: X` noise.
+func analyzeFixedCode(fixedCode string, filename string, analyzers []string) ([]Diagnostic, error) {
+	if filepath.Ext(filename) != ".go" {
+		return nil, nil
 	}
 
-	var response CodeFixResponse
-	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+	tmpPath, err := writeTempPackageCopy(filename, fixedCode)
+	if err != nil {
 		return nil, err
 	}
+	defer os.RemoveAll(filepath.Dir(tmpPath))
 
-	return &response, nil
+	return runAnalyzers(tmpPath, analyzers)
 }
 
-func checkServerAvailable() bool {
-	client := &http.Client{Timeout: 5 * time.Second}
-	resp, err := client.Get(baseURL + "/models")
-	if err != nil {
-		return false
+func validateAndSave(originalFile string, fix *CodeFix, insecure bool) error {
+	ops, hunks := computeHunks(fix.OriginalCode, fix.FixedCode)
+	if len(hunks) == 0 {
+		return fmt.Errorf("fixed code is identical to the original, nothing to apply")
 	}
-	defer resp.Body.Close()
-	return resp.StatusCode == http.StatusOK
-}
-
-func validateAndSave(originalFile string, fix *CodeFix) error {
-	fmt.Print("\nApply these changes? [y/N]: ")
 
 	tty, err := os.Open("/dev/tty")
 	if err != nil {
@@ -267,14 +327,22 @@ func validateAndSave(originalFile string, fix *CodeFix) error {
 	}
 	defer tty.Close()
 
-	var confirm string
-	_, err = fmt.Fscanln(tty, &confirm)
-	if err != nil && err != io.EOF {
-		return fmt.Errorf("error reading input: %v", err)
+	fmt.Printf("\n%d hunk(s) to review:\n\n", len(hunks))
+	accepted, err := reviewHunksInteractive(tty, hunks)
+	if err != nil {
+		return err
 	}
+	if len(accepted) == 0 {
+		return fmt.Errorf("user rejected all hunks")
+	}
+
+	finalContent := applyHunks(ops, hunks, accepted)
+	fix.FixedCode = finalContent
 
-	if strings.ToLower(confirm) != "y" {
-		return fmt.Errorf("user cancelled the operation")
+	if rejName, err := writeRejectFile(originalFile, hunks, accepted); err != nil {
+		return fmt.Errorf("error writing reject file: %v", err)
+	} else if rejName != "" {
+		fmt.Printf("\nRejected hunks saved to %s\n", rejName)
 	}
 
 	// Create backup with timestamp
@@ -284,21 +352,57 @@ func validateAndSave(originalFile string, fix *CodeFix) error {
 	}
 
 	// Write fixed content to original filename
-	if err := os.WriteFile(originalFile, []byte(fix.FixedCode), 0644); err != nil {
+	if err := os.WriteFile(originalFile, []byte(finalContent), 0644); err != nil {
 		return fmt.Errorf("error writing fixed code: %v", err)
 	}
 
 	fmt.Printf("\nBackup saved to %s\n", backupName)
 
-	// Validate the fixed code
-	if fix.Language == "go" && strings.HasSuffix(originalFile, ".go") {
-		fmt.Println("\nValidating Go code...")
-		cmd := exec.Command("go", "build", "-o", "/dev/null", originalFile)
-		if output, err := cmd.CombinedOutput(); err != nil {
-			return fmt.Errorf("build failed: %s\n%s", err, string(output))
+	// Validate the fixed code with the validator registered for its language.
+	validator := validatorForLanguage(fix.Language)
+	if validator == nil {
+		fmt.Printf("\nNo validator registered for %q, skipping validation.\n", fix.Language)
+		return writeFixReport(originalFile, fix, nil)
+	}
+
+	fmt.Printf("\nValidating %s code...\n", fix.Language)
+	result, err := validator.Validate(originalFile, insecure)
+	if err != nil {
+		os.Rename(backupName, originalFile)
+		return fmt.Errorf("error running validator: %v", err)
+	}
+
+	if !result.Passed {
+		if rollbackErr := os.Rename(backupName, originalFile); rollbackErr != nil {
+			return fmt.Errorf("validation failed (%s) and rollback failed: %v\ndiagnostics: %s",
+				result.Tool, rollbackErr, strings.Join(result.Diagnostics, "\n"))
 		}
-		fmt.Println("Code compiled successfully!")
+		writeFixReport(originalFile, fix, &result)
+		return fmt.Errorf("validation failed with %s, rolled back to %s:\n%s",
+			result.Tool, backupName, strings.Join(result.Diagnostics, "\n"))
+	}
+
+	fmt.Println("Code validated successfully!")
+	return writeFixReport(originalFile, fix, &result)
+}
+
+// writeFixReport records the CodeFix alongside its ValidationResult (if any)
+// as a JSON report next to the target file, so fix/validate history can be
+// audited after the fact.
+func writeFixReport(originalFile string, fix *CodeFix, result *ValidationResult) error {
+	report := struct {
+		CodeFix    *CodeFix          `json:"code_fix"`
+		Validation *ValidationResult `json:"validation,omitempty"`
+	}{CodeFix: fix, Validation: result}
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshaling fix report: %v", err)
 	}
 
+	reportName := originalFile + ".fix.json"
+	if err := os.WriteFile(reportName, data, 0644); err != nil {
+		return fmt.Errorf("error writing fix report: %v", err)
+	}
 	return nil
 }
\ No newline at end of file
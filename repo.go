@@ -0,0 +1,253 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FileReport is one file's outcome from `codefixer repo`: whether a fix was
+// produced and applied, and what its language validator reported. Whether
+// the whole tree built afterwards is recorded once, in RepoReport.
+type FileReport struct {
+	File       string            `json:"file"`
+	Fix        *CodeFix          `json:"fix,omitempty"`
+	Applied    bool              `json:"applied"`
+	Backup     string            `json:"backup,omitempty"`
+	Validation *ValidationResult `json:"validation,omitempty"`
+	Error      string            `json:"error,omitempty"`
+}
+
+// RepoReport is the aggregate result written after a `codefixer repo` run.
+type RepoReport struct {
+	BuildCmd      string       `json:"build_cmd"`
+	Files         []FileReport `json:"files"`
+	FinalBuildOK  bool         `json:"final_build_ok"`
+	FinalBuildLog string       `json:"final_build_log,omitempty"`
+}
+
+// runRepoCommand implements `codefixer repo`: build the project, group the
+// resulting diagnostics by file, fix each file concurrently, then rebuild to
+// confirm the tree compiles end to end.
+func runRepoCommand(args []string) error {
+	fs := flag.NewFlagSet("repo", flag.ExitOnError)
+	buildCmd := fs.String("build-cmd", "go build ./...", "command used to build/test the project")
+	jobs := fs.Int("jobs", 4, "maximum number of files to fix concurrently")
+	stream := fs.Bool("stream", true, "stream the LLM response and print explanation tokens as they arrive")
+	noStream := fs.Bool("no-stream", false, "disable streaming and wait for the full response")
+	insecure := fs.Bool("insecure", false, "run language validators without sandboxing (bubblewrap/nsjail)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	streaming := *stream && !*noStream
+
+	cfg := loadConfig()
+	backend, err := newBackend(cfg)
+	if err != nil {
+		return fmt.Errorf("error configuring backend: %v", err)
+	}
+	if !backend.Ping() {
+		return fmt.Errorf("%s backend not available at %s", backend.Name(), cfg.BaseURL)
+	}
+
+	fmt.Printf("Running build command: %s\n", *buildCmd)
+	buildOutput, _ := runBuildCommand(*buildCmd)
+	diagnosticsByFile := groupDiagnosticsByFile(parseBuildDiagnostics(buildOutput))
+	if len(diagnosticsByFile) == 0 {
+		fmt.Println("Build succeeded (or produced no parseable diagnostics); nothing to fix.")
+		return nil
+	}
+
+	fmt.Printf("Found diagnostics in %d file(s); fixing with up to %d worker(s)...\n", len(diagnosticsByFile), *jobs)
+	reports := fixFilesConcurrently(diagnosticsByFile, *jobs, streaming, *insecure, backend, cfg)
+
+	fmt.Printf("\nRe-running build command to confirm: %s\n", *buildCmd)
+	finalOutput, finalErr := runBuildCommand(*buildCmd)
+
+	report := RepoReport{
+		BuildCmd:      *buildCmd,
+		Files:         reports,
+		FinalBuildOK:  finalErr == nil,
+		FinalBuildLog: finalOutput,
+	}
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshaling repo report: %v", err)
+	}
+	if err := os.WriteFile("codefixer-repo-report.json", data, 0644); err != nil {
+		return fmt.Errorf("error writing repo report: %v", err)
+	}
+
+	if report.FinalBuildOK {
+		fmt.Println("\nBuild succeeded after fixes. Report written to codefixer-repo-report.json")
+	} else {
+		fmt.Println("\nBuild still fails after fixes. Report written to codefixer-repo-report.json")
+	}
+	return nil
+}
+
+// runBuildCommand runs cmd through the shell and returns its combined
+// stdout+stderr along with a non-nil error on a non-zero exit.
+func runBuildCommand(cmd string) (string, error) {
+	c := exec.Command("sh", "-c", cmd)
+	output, err := c.CombinedOutput()
+	return string(output), err
+}
+
+var (
+	goOrCCDiagnosticRE = regexp.MustCompile(`^([^:\s][^:]*):(\d+):(\d+)?:?\s*(.*)$`)
+	javacDiagnosticRE  = regexp.MustCompile(`^([^:\s][^:]*):(\d+):\s*(error|warning):\s*(.*)$`)
+	tscDiagnosticRE    = regexp.MustCompile(`^([^(\s][^(]*)\((\d+),(\d+)\):\s*(error|warning)\s+(TS\d+:.*)$`)
+)
+
+// parseBuildDiagnostics extracts file:line:col diagnostics from a build
+// command's output, recognizing the Go/gcc/g++, javac and tsc formats.
+func parseBuildDiagnostics(output string) []Diagnostic {
+	var diagnostics []Diagnostic
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		if m := tscDiagnosticRE.FindStringSubmatch(line); m != nil {
+			lineNum, _ := strconv.Atoi(m[2])
+			col, _ := strconv.Atoi(m[3])
+			diagnostics = append(diagnostics, Diagnostic{File: m[1], Line: lineNum, Column: col, Message: m[5]})
+			continue
+		}
+		if m := javacDiagnosticRE.FindStringSubmatch(line); m != nil {
+			lineNum, _ := strconv.Atoi(m[2])
+			diagnostics = append(diagnostics, Diagnostic{File: m[1], Line: lineNum, Message: m[4]})
+			continue
+		}
+		if m := goOrCCDiagnosticRE.FindStringSubmatch(line); m != nil {
+			lineNum, _ := strconv.Atoi(m[2])
+			col, _ := strconv.Atoi(m[3])
+			diagnostics = append(diagnostics, Diagnostic{File: m[1], Line: lineNum, Column: col, Message: strings.TrimSpace(m[4])})
+		}
+	}
+	return diagnostics
+}
+
+// groupDiagnosticsByFile buckets diagnostics by the file they apply to,
+// skipping any file that doesn't exist on disk (e.g. stdlib frames).
+func groupDiagnosticsByFile(diagnostics []Diagnostic) map[string][]Diagnostic {
+	grouped := make(map[string][]Diagnostic)
+	for _, d := range diagnostics {
+		if _, err := os.Stat(d.File); err != nil {
+			continue
+		}
+		grouped[d.File] = append(grouped[d.File], d)
+	}
+	return grouped
+}
+
+// stdoutMu serializes the per-file completion output fixOneFile prints,
+// since fixFilesConcurrently's workers all write to stdout at once.
+var stdoutMu sync.Mutex
+
+// fixFilesConcurrently dispatches one fix job per file, bounded by a worker
+// pool of size jobs, backing up and writing each file independently.
+func fixFilesConcurrently(diagnosticsByFile map[string][]Diagnostic, jobs int, streaming bool, insecure bool, backend Backend, cfg Config) []FileReport {
+	if jobs < 1 {
+		jobs = 1
+	}
+
+	files := make([]string, 0, len(diagnosticsByFile))
+	for file := range diagnosticsByFile {
+		files = append(files, file)
+	}
+
+	reports := make([]FileReport, len(files))
+	sem := make(chan struct{}, jobs)
+	var wg sync.WaitGroup
+
+	for i, file := range files {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, file string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			reports[i] = fixOneFile(file, diagnosticsByFile[file], streaming, insecure, backend, cfg)
+		}(i, file)
+	}
+	wg.Wait()
+
+	return reports
+}
+
+// fixOneFile fixes a single file given its build diagnostics, backs up the
+// original, writes the fix in place, and validates it with the Validator
+// registered for its language, rolling the file back to its backup on
+// failure - the same verify-or-rollback contract validateAndSave gives the
+// single-file flow. Streamed explanation tokens are never printed live here
+// (printLive=false), since several of these run concurrently under
+// fixFilesConcurrently; instead the explanation is printed once, in full,
+// under stdoutMu once this file's fix is ready.
+func fixOneFile(file string, diagnostics []Diagnostic, streaming bool, insecure bool, backend Backend, cfg Config) FileReport {
+	report := FileReport{File: file}
+
+	content, err := os.ReadFile(file)
+	if err != nil {
+		report.Error = fmt.Sprintf("error reading file: %v", err)
+		return report
+	}
+
+	fix, err := fixCodeWithDiagnostics(string(content), file, diagnostics, DefaultAnalyzeOptions, streaming, false, backend, cfg)
+	if err != nil {
+		report.Error = fmt.Sprintf("error fixing code: %v", err)
+		return report
+	}
+	report.Fix = fix
+
+	stdoutMu.Lock()
+	fmt.Printf("\n--- %s ---\n%s\n", file, fix.Explanation)
+	stdoutMu.Unlock()
+
+	backupName := fmt.Sprintf("%s.%s.bak", file, time.Now().Format("20060102150405"))
+	if err := os.Rename(file, backupName); err != nil {
+		report.Error = fmt.Sprintf("error creating backup: %v", err)
+		return report
+	}
+	report.Backup = backupName
+
+	if err := os.WriteFile(file, []byte(fix.FixedCode), 0644); err != nil {
+		report.Error = fmt.Sprintf("error writing fixed code: %v", err)
+		os.Rename(backupName, file)
+		return report
+	}
+
+	validator := validatorForLanguage(fix.Language)
+	if validator == nil {
+		report.Applied = true
+		return report
+	}
+
+	result, err := validator.Validate(file, insecure)
+	if err != nil {
+		os.Rename(backupName, file)
+		report.Error = fmt.Sprintf("error running validator: %v", err)
+		return report
+	}
+	report.Validation = &result
+
+	if !result.Passed {
+		if rollbackErr := os.Rename(backupName, file); rollbackErr != nil {
+			report.Error = fmt.Sprintf("validation failed (%s) and rollback failed: %v", result.Tool, rollbackErr)
+			return report
+		}
+		report.Error = fmt.Sprintf("validation failed with %s, rolled back to %s", result.Tool, backupName)
+		return report
+	}
+
+	report.Applied = true
+	return report
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// streamChunk is a single OpenAI-style `text/event-stream` chunk: the
+// incremental `delta.content` fragment, plus usage accounting when present
+// on the final chunk (requested via stream_options.include_usage).
+type streamChunk struct {
+	Choices []struct {
+		Delta struct {
+			Content string `json:"content"`
+		} `json:"delta"`
+	} `json:"choices"`
+	Usage *Usage `json:"usage"`
+}
+
+// sendChatRequestStream sends request against b with streaming enabled and
+// consumes the `text/event-stream` response, accumulating the full JSON body
+// for the caller to parse once the stream ends. When printLive is true,
+// `explanation` tokens are also printed to stdout as they arrive; callers
+// running several of these concurrently must pass printLive=false, since
+// interleaved writes from explanationPrinter would otherwise garble stdout.
+func sendChatRequestStream(b *openAICompatibleBackend, request CodeFixRequest, printLive bool) (*CodeFixResponse, error) {
+	request.Stream = true
+
+	client := &http.Client{Timeout: b.timeout}
+
+	requestBody, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := doWithRetry(client, func() (*http.Request, error) {
+		req, err := http.NewRequest("POST", b.baseURL+"/chat/completions", bytes.NewBuffer(requestBody))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept", "text/event-stream")
+		b.authorize(req)
+		return req, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("API request failed: %s\nResponse: %s", resp.Status, body)
+	}
+
+	var content strings.Builder
+	var usage *Usage
+	printer := newExplanationPrinter()
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		payload := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if payload == "[DONE]" {
+			break
+		}
+
+		var chunk streamChunk
+		if err := json.Unmarshal([]byte(payload), &chunk); err != nil {
+			continue // malformed chunk; drop it and keep reading the stream
+		}
+		if chunk.Usage != nil {
+			usage = chunk.Usage
+		}
+		if len(chunk.Choices) == 0 {
+			continue
+		}
+
+		content.WriteString(chunk.Choices[0].Delta.Content)
+		if printLive {
+			printer.feed(content.String())
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading stream: %v", err)
+	}
+	if printLive {
+		printer.finish()
+	}
+
+	response := &CodeFixResponse{
+		Choices: []Choice{{Message: ChoiceMessage{Content: content.String()}}},
+		Usage:   usage,
+	}
+	return response, nil
+}
+
+// explanationPrinter incrementally extracts the `explanation` field out of a
+// partially-streamed JSON document and prints only the newly arrived
+// characters, decoded. It deliberately treats everything outside that field
+// as an opaque, not-yet-parseable fragment (mirroring json.RawMessage
+// semantics) since `fixed_code` and friends are only usable once the stream
+// completes.
+type explanationPrinter struct {
+	printedDecoded string
+	started        bool
+}
+
+func newExplanationPrinter() *explanationPrinter {
+	return &explanationPrinter{}
+}
+
+var explanationFieldRE = regexp.MustCompile(`"explanation"\s*:\s*"`)
+
+// feed is called with the full accumulated content buffer on every chunk;
+// it decodes whatever part of the "explanation" string value is safe to
+// decode so far and prints whatever of that is newly available since the
+// last call. A trailing escape sequence split across a chunk boundary is
+// withheld until the rest of it arrives, rather than printed through.
+func (p *explanationPrinter) feed(buf string) {
+	loc := explanationFieldRE.FindStringIndex(buf)
+	if loc == nil {
+		return
+	}
+
+	value := buf[loc[1]:]
+	if end := unescapedQuoteIndex(value); end >= 0 {
+		value = value[:end]
+	}
+
+	decoded, err := decodeJSONStringPrefix(value)
+	if err != nil || !strings.HasPrefix(decoded, p.printedDecoded) {
+		return // not enough of the escape sequence has arrived yet
+	}
+
+	if !p.started {
+		fmt.Print("\nExplanation (streaming): ")
+		p.started = true
+	}
+
+	if len(decoded) > len(p.printedDecoded) {
+		fmt.Print(decoded[len(p.printedDecoded):])
+		p.printedDecoded = decoded
+	}
+}
+
+func (p *explanationPrinter) finish() {
+	if p.started {
+		fmt.Println()
+	}
+}
+
+// decodeJSONStringPrefix decodes raw (the unquoted contents of a JSON
+// string, still `\`-escaped) into its real characters, first trimming any
+// trailing escape sequence that hasn't fully arrived yet so a chunk
+// boundary landing mid-escape doesn't get misdecoded or printed literally.
+func decodeJSONStringPrefix(raw string) (string, error) {
+	safe := trimIncompleteTrailingEscape(raw)
+
+	var decoded string
+	if err := json.Unmarshal([]byte(`"`+safe+`"`), &decoded); err != nil {
+		return "", err
+	}
+	return decoded, nil
+}
+
+// trimIncompleteTrailingEscape drops a trailing `\` or partial `\uXXXX`
+// escape that hasn't fully arrived yet, so the remainder is always valid to
+// decode as a complete JSON string. A trailing `\uXXXX` that is itself a
+// lone UTF-16 high surrogate is also withheld: its matching low surrogate
+// may still be in the next chunk, and decoding it alone would silently
+// produce a replacement character (U+FFFD) instead of waiting for the real
+// one, permanently desyncing the printed/decoded cursors.
+func trimIncompleteTrailingEscape(s string) string {
+	trailingBackslashes := 0
+	for trailingBackslashes < len(s) && s[len(s)-1-trailingBackslashes] == '\\' {
+		trailingBackslashes++
+	}
+	if trailingBackslashes%2 == 1 {
+		return trimIncompleteTrailingEscape(s[:len(s)-1])
+	}
+
+	if idx := strings.LastIndex(s, `\u`); idx != -1 && idx >= len(s)-6 {
+		preceding := 0
+		for idx-1-preceding >= 0 && s[idx-1-preceding] == '\\' {
+			preceding++
+		}
+		if preceding%2 == 1 {
+			return s // the `\` at idx is itself escaped, so this `u` is literal text
+		}
+		hex := s[idx+2:]
+		if len(hex) < 4 {
+			return trimIncompleteTrailingEscape(s[:idx])
+		}
+		if codepoint, err := strconv.ParseUint(hex[:4], 16, 32); err == nil && codepoint >= 0xD800 && codepoint <= 0xDBFF {
+			return trimIncompleteTrailingEscape(s[:idx])
+		}
+	}
+	return s
+}
+
+// unescapedQuoteIndex returns the index of the first unescaped `"` in s, or
+// -1 if the string value hasn't been closed yet.
+func unescapedQuoteIndex(s string) int {
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' {
+			i++
+			continue
+		}
+		if s[i] == '"' {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"testing"
+)
+
+// captureStdout redirects os.Stdout for the duration of fn and returns
+// everything written to it.
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("os.Pipe: %v", err)
+	}
+	original := os.Stdout
+	os.Stdout = w
+
+	fn()
+
+	os.Stdout = original
+	w.Close()
+
+	out, err := io.ReadAll(bufio.NewReader(r))
+	if err != nil {
+		t.Fatalf("reading captured stdout: %v", err)
+	}
+	return string(out)
+}
+
+// feedChunks simulates sendChatRequestStream's loop: it grows buf by each
+// delta in turn and calls feed with the full accumulated content so far,
+// exactly as the streaming loop does.
+func feedChunks(p *explanationPrinter, deltas []string) {
+	var buf string
+	for _, d := range deltas {
+		buf += d
+		p.feed(buf)
+	}
+}
+
+func TestExplanationPrinterFeedSplitAcrossChunks(t *testing.T) {
+	// "\n" (a two-byte escape) lands split exactly across a chunk boundary,
+	// the bug the maintainer's review reproduced.
+	deltas := []string{
+		`{"explanation": "line one\`,
+		`nline two", "fixed_code": "...`,
+	}
+
+	p := newExplanationPrinter()
+	out := captureStdout(t, func() {
+		feedChunks(p, deltas)
+		p.finish()
+	})
+
+	want := "\nExplanation (streaming): line one\nline two\n"
+	if out != want {
+		t.Errorf("got %q, want %q", out, want)
+	}
+}
+
+func TestExplanationPrinterFeedSplitSurrogatePair(t *testing.T) {
+	// The high surrogate of "😀" (an emoji) lands at the very end
+	// of a chunk, with its low surrogate only arriving in the next one.
+	deltas := []string{
+		`{"explanation": "hi \uD83D`,
+		`\uDE00 bye"`,
+	}
+
+	p := newExplanationPrinter()
+	out := captureStdout(t, func() {
+		feedChunks(p, deltas)
+		p.finish()
+	})
+
+	want := "\nExplanation (streaming): hi \U0001F600 bye\n"
+	if out != want {
+		t.Errorf("got %q, want %q", out, want)
+	}
+}
+
+func TestExplanationPrinterFeedNoExplanationYet(t *testing.T) {
+	p := newExplanationPrinter()
+	out := captureStdout(t, func() {
+		p.feed(`{"fixed_code": "foo`)
+		p.finish()
+	})
+
+	if out != "" {
+		t.Errorf("expected no output before the explanation field arrives, got %q", out)
+	}
+}
+
+func TestDecodeJSONStringPrefix(t *testing.T) {
+	cases := []struct {
+		name string
+		raw  string
+		want string
+	}{
+		{"plain", `hello world`, "hello world"},
+		{"complete escape", `hello\nworld`, "hello\nworld"},
+		{"trailing lone backslash", `hello\`, "hello"},
+		{"trailing partial unicode escape", `hello\u00`, "hello"},
+		{"trailing lone high surrogate", `hi \uD83D`, "hi "},
+		{"complete surrogate pair", `hi \uD83D` + `\uDE00 bye`, "hi \U0001F600 bye"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := decodeJSONStringPrefix(c.raw)
+			if err != nil {
+				t.Fatalf("decodeJSONStringPrefix(%q): %v", c.raw, err)
+			}
+			if got != c.want {
+				t.Errorf("decodeJSONStringPrefix(%q) = %q, want %q", c.raw, got, c.want)
+			}
+		})
+	}
+}
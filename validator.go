@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// ValidationResult captures the outcome of running a language-specific
+// validation tool against a fixed file. It is recorded alongside the
+// CodeFix so users can audit exactly what was checked and how it went.
+type ValidationResult struct {
+	Tool        string   `json:"tool"`
+	ExitCode    int      `json:"exit_code"`
+	Diagnostics []string `json:"diagnostics,omitempty"`
+	Passed      bool     `json:"passed"`
+}
+
+// Validator checks a fixed file on disk and reports whether it is valid.
+// Implementations shell out to language-specific tools and optionally run
+// them inside a sandbox.
+type Validator interface {
+	Validate(path string, insecure bool) (ValidationResult, error)
+}
+
+// validatorForLanguage returns the Validator responsible for the given
+// language, or nil if no validator is registered for it.
+func validatorForLanguage(language string) Validator {
+	switch strings.ToLower(language) {
+	case "go":
+		return goValidator{}
+	case "python":
+		return pythonValidator{}
+	case "javascript":
+		return javascriptValidator{}
+	case "java":
+		return javaValidator{}
+	case "c++":
+		return cppValidator{}
+	default:
+		return nil
+	}
+}
+
+// runValidationTool executes a validation command, sandboxing it with
+// bubblewrap unless insecure is true, and turns the result into a
+// ValidationResult.
+func runValidationTool(tool string, insecure bool, name string, args ...string) (ValidationResult, error) {
+	cmdName := name
+	cmdArgs := args
+	if !insecure {
+		cmdName, cmdArgs = sandboxCommand(name, args)
+	}
+
+	cmd := exec.Command(cmdName, cmdArgs...)
+	output, err := cmd.CombinedOutput()
+
+	result := ValidationResult{
+		Tool:        tool,
+		Diagnostics: splitNonEmptyLines(string(output)),
+		Passed:      err == nil,
+	}
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		result.ExitCode = exitErr.ExitCode()
+	} else if err != nil {
+		return result, fmt.Errorf("running %s: %w", tool, err)
+	}
+
+	return result, nil
+}
+
+// sandboxCommand wraps a command so it runs under bubblewrap with a
+// read-only view of the filesystem and no network access, except for a
+// writable scratch area: tools like `go build` need somewhere to write
+// GOCACHE/TMPDIR, and without it they fail for reasons unrelated to the
+// code being validated. Falls back to nsjail if bubblewrap is not on PATH.
+func sandboxCommand(name string, args []string) (string, []string) {
+	tmpDir := os.TempDir()
+	scratchCache := tmpDir + "/codefixer-sandbox-gocache"
+	os.MkdirAll(scratchCache, 0755)
+
+	sandboxArgs := []string{
+		"--ro-bind", "/", "/",
+		"--dev", "/dev",
+		"--bind", tmpDir, tmpDir,
+		"--setenv", "TMPDIR", tmpDir,
+		"--setenv", "GOCACHE", scratchCache,
+		"--unshare-all",
+		"--die-with-parent",
+		"--",
+		name,
+	}
+	sandboxArgs = append(sandboxArgs, args...)
+
+	if _, err := exec.LookPath("bwrap"); err == nil {
+		return "bwrap", sandboxArgs
+	}
+	if _, err := exec.LookPath("nsjail"); err == nil {
+		nsjailArgs := []string{
+			"--config", "/etc/codefixer/nsjail.cfg",
+			"--bindmount_rw", tmpDir,
+			"--env", "TMPDIR=" + tmpDir,
+			"--env", "GOCACHE=" + scratchCache,
+			"--",
+			name,
+		}
+		nsjailArgs = append(nsjailArgs, args...)
+		return "nsjail", nsjailArgs
+	}
+	// Neither sandbox is available; run unsandboxed rather than fail outright.
+	return name, args
+}
+
+func splitNonEmptyLines(s string) []string {
+	var lines []string
+	for _, line := range strings.Split(s, "\n") {
+		if trimmed := strings.TrimSpace(line); trimmed != "" {
+			lines = append(lines, trimmed)
+		}
+	}
+	return lines
+}
+
+type goValidator struct{}
+
+func (goValidator) Validate(path string, insecure bool) (ValidationResult, error) {
+	return runValidationTool("go build", insecure, "go", "build", "-o", "/dev/null", path)
+}
+
+type pythonValidator struct{}
+
+func (pythonValidator) Validate(path string, insecure bool) (ValidationResult, error) {
+	return runValidationTool("pyflakes", insecure, "pyflakes", path)
+}
+
+type javascriptValidator struct{}
+
+func (javascriptValidator) Validate(path string, insecure bool) (ValidationResult, error) {
+	return runValidationTool("node --check", insecure, "node", "--check", path)
+}
+
+type javaValidator struct{}
+
+func (javaValidator) Validate(path string, insecure bool) (ValidationResult, error) {
+	outDir, err := os.MkdirTemp("", "codefixer-javac")
+	if err != nil {
+		return ValidationResult{}, fmt.Errorf("creating javac output dir: %w", err)
+	}
+	defer os.RemoveAll(outDir)
+
+	return runValidationTool("javac", insecure, "javac", "-d", outDir, path)
+}
+
+type cppValidator struct{}
+
+func (cppValidator) Validate(path string, insecure bool) (ValidationResult, error) {
+	return runValidationTool("g++ -fsyntax-only", insecure, "g++", "-fsyntax-only", path)
+}